@@ -2,17 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 
 	"truthnet/api-go/contracts"
 )
 
 // callVisualAgent sends request to visual agent and returns response
-func callVisualAgent(requestID, mediaPath string, mediaType contracts.MediaType) *contracts.AgentResponse {
+func callVisualAgent(ctx context.Context, requestID, mediaPath string, mediaType contracts.MediaType) *contracts.AgentResponse {
 	fmt.Printf("🎨 Calling Visual Agent for request %s\n", requestID)
 
 	request := contracts.AgentRequest{
@@ -23,7 +24,7 @@ func callVisualAgent(requestID, mediaPath string, mediaType contracts.MediaType)
 		Options:   make(map[string]interface{}),
 	}
 
-	response := callAgent(VisualAgentURL+"/analyze", request)
+	response := callAgent(ctx, VisualAgentURL+"/analyze", request)
 	if response != nil {
 		fmt.Printf("   ✓ Visual Agent: Risk %.2f, %d signals\n", response.RiskScore, len(response.Signals))
 	}
@@ -31,7 +32,7 @@ func callVisualAgent(requestID, mediaPath string, mediaType contracts.MediaType)
 }
 
 // callMetadataAgent sends request to metadata agent and returns response
-func callMetadataAgent(requestID, mediaPath string, mediaType contracts.MediaType) *contracts.AgentResponse {
+func callMetadataAgent(ctx context.Context, requestID, mediaPath string, mediaType contracts.MediaType) *contracts.AgentResponse {
 	fmt.Printf("📋 Calling Metadata Agent for request %s\n", requestID)
 
 	request := contracts.AgentRequest{
@@ -42,27 +43,24 @@ func callMetadataAgent(requestID, mediaPath string, mediaType contracts.MediaTyp
 		Options:   make(map[string]interface{}),
 	}
 
-	response := callAgent(MetadataAgentURL+"/analyze", request)
+	response := callAgent(ctx, MetadataAgentURL+"/analyze", request)
 	if response != nil {
 		fmt.Printf("   ✓ Metadata Agent: Risk %.2f, %d signals\n", response.RiskScore, len(response.Signals))
 	}
 	return response
 }
 
-// callAgent is a generic HTTP client for calling agent services
-func callAgent(url string, request contracts.AgentRequest) *contracts.AgentResponse {
+// callAgent is a generic HTTP client for calling agent services. The
+// deadline is carried entirely by ctx rather than a fixed client timeout,
+// so callers control how long they're willing to wait.
+func callAgent(ctx context.Context, url string, request contracts.AgentRequest) *contracts.AgentResponse {
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return createErrorResponse(request, "MARSHAL_ERROR", err.Error())
 	}
 
-	// Create HTTP request with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return createErrorResponse(request, "REQUEST_CREATE_ERROR", err.Error())
 	}
@@ -70,8 +68,11 @@ func callAgent(url string, request contracts.AgentRequest) *contracts.AgentRespo
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	resp, err := client.Do(httpReq)
+	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return createDeadlineResponse(request)
+		}
 		return createErrorResponse(request, "NETWORK_ERROR", err.Error())
 	}
 	defer resp.Body.Close()
@@ -110,3 +111,20 @@ func createErrorResponse(request contracts.AgentRequest, code, message string) *
 		},
 	}
 }
+
+// createDeadlineResponse creates a partial response when an agent doesn't
+// answer before its deadline, so calculateVerdict can still reach a
+// best-effort verdict from whichever agent did respond.
+func createDeadlineResponse(request contracts.AgentRequest) *contracts.AgentResponse {
+	return &contracts.AgentResponse{
+		RequestID: request.RequestID,
+		AgentType: request.AgentType,
+		Status:    contracts.StatusPartial,
+		RiskScore: 0.0,
+		Signals:   []contracts.Signal{},
+		Error: &contracts.AgentError{
+			Code:    "DEADLINE_EXCEEDED",
+			Message: "agent did not respond before its deadline",
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"truthnet/api-go/contracts"
+	"truthnet/api-go/render"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// assembleAndDispatch is the single code path that turns a fully-hashed,
+// on-disk upload into either a cache hit or a queued analysis job. Both
+// the single-shot POST /analyze and the chunked upload's verify step feed
+// through here, so there is exactly one place that hands media to
+// callVisualAgent/callMetadataAgent.
+func assembleAndDispatch(c *gin.Context, oid, stagingPath, filename string, mediaType contracts.MediaType, startTime time.Time, callbackURL, callbackAuth string) {
+	c.Header("ETag", `"`+oid+`"`)
+
+	cached, found, err := resultStore.GetByOID(oid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read result store"})
+		return
+	}
+
+	// A client that already holds the verdict for this exact content can
+	// skip re-fetching the body entirely.
+	if found && c.GetHeader("If-None-Match") == `"`+oid+`"` {
+		os.Remove(stagingPath)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	requestID := uuid.New().String()
+
+	if found {
+		os.Remove(stagingPath)
+		if err := resultStore.Alias(requestID, oid); err != nil {
+			fmt.Printf("⚠️  Failed to alias %s to %s: %v\n", requestID, oid, err)
+		}
+
+		fmt.Printf("♻️  Cache hit for OID %s, aliased to request %s\n", oid, requestID)
+
+		cached.RequestID = requestID
+		if callbackURL != "" {
+			callbackManager.Enqueue(requestID, callbackURL, callbackAuth, cached)
+		}
+		c.Header("X-TruthNet-Cache", "hit")
+		render.Negotiate(c, http.StatusOK, cached)
+		return
+	}
+
+	mediaPath, err := commitObject(stagingPath, oid, filename)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to store file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	absPath, err := filepath.Abs(mediaPath)
+	if err != nil {
+		absPath = mediaPath
+	}
+
+	fmt.Printf("📁 File saved: %s\n", filename)
+	fmt.Printf("🔍 Request ID: %s (OID %s)\n", requestID, oid)
+	fmt.Printf("📊 Media Type: %s\n", mediaType)
+
+	// Enqueue the agent fan-out and return immediately; the caller polls
+	// GET /result/:request_id for the finished verdict, or supplies a
+	// callback_url to be notified instead.
+	jobManager.Submit(analysisJob{
+		requestID:    requestID,
+		oid:          oid,
+		mediaPath:    absPath,
+		mediaType:    mediaType,
+		startTime:    startTime,
+		callbackURL:  callbackURL,
+		callbackAuth: callbackAuth,
+	})
+
+	c.Header("X-TruthNet-Cache", "miss")
+	render.Negotiate(c, http.StatusAccepted, gin.H{
+		"request_id":  requestID,
+		"status":      "pending",
+		"content_oid": oid,
+	})
+}
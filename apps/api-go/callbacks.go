@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// callbackHTTPClient delivers webhook POSTs through a transport whose
+// DialContext resolves the host itself and rejects disallowed addresses at
+// dial time. validateCallbackURL's check happens at Enqueue time; without
+// pinning the dial to an address vetted then-and-there, a rebinding DNS
+// record could return a public IP to the validator and a loopback/link-local
+// one to http.DefaultClient's own resolution moments later, bypassing the
+// SSRF guard entirely (DNS rebinding TOCTOU).
+var callbackHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: dialVettedCallbackAddr,
+	},
+}
+
+// dialVettedCallbackAddr resolves addr's host, rejects it if every resolved
+// IP is disallowed, and dials the first allowed IP directly - so the address
+// that passed the SSRF check is the one actually connected to, not whatever
+// a second DNS lookup returns.
+func dialVettedCallbackAddr(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("split callback address: %w", err)
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve callback host: %w", err)
+	}
+
+	var dialer net.Dialer
+	var lastErr error
+	for _, ip := range ips {
+		if isDisallowedCallbackIP(ip) {
+			lastErr = fmt.Errorf("callback host %s resolves to a disallowed address (%s)", host, ip)
+			continue
+		}
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("callback host %s did not resolve to any address", host)
+	}
+	return nil, lastErr
+}
+
+// callbackBackoff is the retry schedule for non-2xx callback deliveries.
+var callbackBackoff = []time.Duration{
+	1 * time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+}
+
+// CallbackAttempt records the outcome of a single delivery attempt.
+type CallbackAttempt struct {
+	Timestamp  time.Time `json:"timestamp"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// CallbackDelivery tracks the webhook delivery state for a single request,
+// persisted so retries can resume across restarts. Auth is tagged json:"-"
+// because this struct doubles as the body GET /callbacks/:request_id
+// returns to API callers; the bearer token must never round-trip back out
+// that endpoint. The result store persists Auth separately via
+// PersistedCallback so a restart can still resume authed deliveries.
+type CallbackDelivery struct {
+	RequestID string            `json:"request_id"`
+	URL       string            `json:"url"`
+	Auth      string            `json:"-"`
+	Status    string            `json:"status"` // pending, delivered, failed
+	Attempts  []CallbackAttempt `json:"attempts"`
+}
+
+// PersistedCallback is the on-disk record of a CallbackDelivery, including
+// the bearer token CallbackDelivery itself withholds from JSON so that
+// GET /callbacks/:request_id can't leak it back to API callers.
+type PersistedCallback struct {
+	RequestID string            `json:"request_id"`
+	URL       string            `json:"url"`
+	Auth      string            `json:"auth,omitempty"`
+	Status    string            `json:"status"`
+	Attempts  []CallbackAttempt `json:"attempts"`
+}
+
+// toPersisted and toDelivery convert between the API-facing CallbackDelivery
+// (Auth withheld) and its on-disk PersistedCallback representation (Auth
+// included), so the result store can resume authed deliveries after a
+// restart without ever serializing the token back out
+// GET /callbacks/:request_id.
+func (cb CallbackDelivery) toPersisted() PersistedCallback {
+	return PersistedCallback{
+		RequestID: cb.RequestID,
+		URL:       cb.URL,
+		Auth:      cb.Auth,
+		Status:    cb.Status,
+		Attempts:  cb.Attempts,
+	}
+}
+
+func (p PersistedCallback) toDelivery() CallbackDelivery {
+	return CallbackDelivery{
+		RequestID: p.RequestID,
+		URL:       p.URL,
+		Auth:      p.Auth,
+		Status:    p.Status,
+		Attempts:  p.Attempts,
+	}
+}
+
+// CallbackManager delivers OrchestratorResponses to client-supplied webhook
+// URLs, retrying non-2xx responses on the backoff schedule in
+// callbackBackoff and persisting delivery state in the result store.
+type CallbackManager struct {
+	store ResultStore
+}
+
+// NewCallbackManager builds a CallbackManager backed by store.
+func NewCallbackManager(store ResultStore) *CallbackManager {
+	return &CallbackManager{store: store}
+}
+
+// Enqueue registers a pending callback for requestID and starts delivering
+// it in the background. resp is the already-computed OrchestratorResponse.
+// callbackURL is re-validated here (in addition to the checks callers
+// should already be doing at request intake) since this is the one path
+// every callback delivery - including resumed ones - funnels through.
+func (cm *CallbackManager) Enqueue(requestID, callbackURL, auth string, resp interface{}) {
+	cb := CallbackDelivery{
+		RequestID: requestID,
+		URL:       callbackURL,
+		Auth:      auth,
+		Status:    "pending",
+	}
+
+	if err := validateCallbackURL(callbackURL); err != nil {
+		cb.Status = "failed"
+		cb.Attempts = []CallbackAttempt{{Timestamp: time.Now(), Error: err.Error()}}
+		fmt.Printf("⚠️  Refusing callback_url for %s: %v\n", requestID, err)
+		cm.persist(cb)
+		return
+	}
+
+	if err := cm.store.PutCallback(requestID, cb); err != nil {
+		fmt.Printf("⚠️  Failed to persist callback for %s: %v\n", requestID, err)
+	}
+
+	go cm.deliver(cb, resp)
+}
+
+// validateCallbackURL rejects anything other than an https URL whose host
+// resolves only to addresses isDisallowedCallbackIP accepts: loopback,
+// private, link-local and unspecified addresses are refused; other
+// non-public ranges (e.g. 100.64.0.0/10) are not checked. callback_url is
+// client-supplied and the server itself originates the delivery request,
+// so without this a caller could point TruthNet at loopback, private, or
+// link-local services (including the visual/metadata agents themselves)
+// and use it as an SSRF proxy; the HMAC signature doesn't help since it
+// only authenticates the payload to whoever receives it.
+func validateCallbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("callback_url must use https")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("callback_url must include a host")
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("resolve callback_url host: %w", err)
+	}
+	for _, ip := range ips {
+		if isDisallowedCallbackIP(ip) {
+			return fmt.Errorf("callback_url host %s resolves to a disallowed address (%s)", host, ip)
+		}
+	}
+
+	return nil
+}
+
+// isDisallowedCallbackIP reports whether ip is loopback, private, link-local,
+// or unspecified - the address ranges a client-supplied webhook must not be
+// able to reach.
+func isDisallowedCallbackIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// ResumePending re-enqueues delivery of every callback left "pending" in
+// the store, so a callback interrupted mid-backoff by a restart isn't
+// stranded showing a status that nothing is actually retrying.
+func (cm *CallbackManager) ResumePending() {
+	pending, err := cm.store.PendingCallbacks()
+	if err != nil {
+		fmt.Printf("⚠️  Failed to list pending callbacks: %v\n", err)
+		return
+	}
+
+	for _, cb := range pending {
+		resp, found, err := cm.store.Get(cb.RequestID)
+		if err != nil || !found {
+			fmt.Printf("⚠️  Skipping stranded callback for %s: no stored result\n", cb.RequestID)
+			continue
+		}
+		fmt.Printf("🔁 Resuming callback delivery for %s\n", cb.RequestID)
+		go cm.deliver(cb, resp)
+	}
+}
+
+// maxCallbackAttempts bounds the total deliveries attempted for a single
+// callback, counting attempts made before any restart. deliver indexes off
+// len(cb.Attempts) (loaded from the persisted record, not a fresh counter)
+// specifically so a process that crash-loops mid-backoff resumes the
+// schedule where it left off instead of restarting it from 1s each time and
+// hammering the receiver indefinitely.
+var maxCallbackAttempts = len(callbackBackoff) + 1
+
+func (cm *CallbackManager) deliver(cb CallbackDelivery, resp interface{}) {
+	if cb.Status == "delivered" || cb.Status == "failed" {
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to marshal callback payload for %s: %v\n", cb.RequestID, err)
+		return
+	}
+
+	for {
+		attempt := len(cb.Attempts)
+		if attempt >= maxCallbackAttempts {
+			cb.Status = "failed"
+			cm.persist(cb)
+			fmt.Printf("⚠️  Callback delivery for %s exhausted retries\n", cb.RequestID)
+			return
+		}
+
+		statusCode, err := cm.send(cb.URL, cb.Auth, body)
+
+		record := CallbackAttempt{Timestamp: time.Now(), StatusCode: statusCode}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		cb.Attempts = append(cb.Attempts, record)
+
+		if err == nil && statusCode >= 200 && statusCode < 300 {
+			cb.Status = "delivered"
+			cm.persist(cb)
+			fmt.Printf("📬 Callback delivered for %s (%d)\n", cb.RequestID, statusCode)
+			return
+		}
+
+		if attempt+1 >= maxCallbackAttempts {
+			cb.Status = "failed"
+			cm.persist(cb)
+			fmt.Printf("⚠️  Callback delivery for %s exhausted retries\n", cb.RequestID)
+			return
+		}
+
+		cm.persist(cb)
+		time.Sleep(callbackBackoff[attempt])
+	}
+}
+
+func (cm *CallbackManager) persist(cb CallbackDelivery) {
+	if err := cm.store.PutCallback(cb.RequestID, cb); err != nil {
+		fmt.Printf("⚠️  Failed to persist callback state for %s: %v\n", cb.RequestID, err)
+	}
+}
+
+// send POSTs body to url, signing it with TRUTHNET_WEBHOOK_SECRET when set
+// and attaching auth as a bearer token when provided.
+func (cm *CallbackManager) send(url, auth string, body []byte) (int, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("build callback request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/vnd.truthnet.verdict+json")
+	if auth != "" {
+		req.Header.Set("Authorization", "Bearer "+auth)
+	}
+	if secret := os.Getenv("TRUTHNET_WEBHOOK_SECRET"); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-TruthNet-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := callbackHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
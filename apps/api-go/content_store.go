@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// stagingDir holds uploads while their SHA-256 is computed, before they're
+// either discarded (cache hit) or moved into their content-addressed home.
+const stagingDir = MediaTempDir + "/staging"
+
+// saveWithOID streams the uploaded file to a staging path while computing
+// its SHA-256 digest, returning the hex digest alongside the staging path.
+func saveWithOID(file *multipart.FileHeader) (oid string, path string, err error) {
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return "", "", fmt.Errorf("create staging dir: %w", err)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("open upload: %w", err)
+	}
+	defer src.Close()
+
+	stagingPath := filepath.Join(stagingDir, uuid.New().String())
+	dst, err := os.Create(stagingPath)
+	if err != nil {
+		return "", "", fmt.Errorf("create staging file: %w", err)
+	}
+	defer dst.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(dst, io.TeeReader(src, hasher)); err != nil {
+		os.Remove(stagingPath)
+		return "", "", fmt.Errorf("copy upload: %w", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), stagingPath, nil
+}
+
+// sanitizeFilename strips any directory components from a caller-supplied
+// filename so it can't be used to escape MediaTempDir via "../" segments.
+// It falls back to "file" if nothing usable remains.
+func sanitizeFilename(filename string) string {
+	base := filepath.Base(filename)
+	if base == "" || base == "." || base == ".." || base == string(filepath.Separator) {
+		return "file"
+	}
+	return base
+}
+
+// objectPath returns the on-disk home for content addressed by oid.
+func objectPath(oid, filename string) string {
+	return filepath.Join(MediaTempDir, oid, sanitizeFilename(filename))
+}
+
+// commitObject moves a staged upload into its content-addressed home. If
+// the object already exists on disk (another upload of the same bytes won
+// the race), the staging copy is discarded and the existing bytes are kept.
+func commitObject(stagingPath, oid, filename string) (string, error) {
+	dir := filepath.Join(MediaTempDir, oid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		os.Remove(stagingPath)
+		return "", fmt.Errorf("create object dir: %w", err)
+	}
+
+	dest := objectPath(oid, filename)
+	if _, err := os.Stat(dest); err == nil {
+		os.Remove(stagingPath)
+		return dest, nil
+	}
+
+	if err := os.Rename(stagingPath, dest); err != nil {
+		os.Remove(stagingPath)
+		return "", fmt.Errorf("commit object: %w", err)
+	}
+
+	return dest, nil
+}
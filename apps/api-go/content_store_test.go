@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestFileHeader builds a *multipart.FileHeader backed by content, named
+// filename, the way c.FormFile would hand one to saveWithOID.
+func newTestFileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatalf("write form file: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	r := multipart.NewReader(&buf, w.Boundary())
+	form, err := r.ReadForm(int64(len(content)) + 1024)
+	if err != nil {
+		t.Fatalf("read form: %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+
+	return form.File["file"][0]
+}
+
+func TestSaveWithOIDUniqueStagingPaths(t *testing.T) {
+	t.Cleanup(func() { os.RemoveAll(MediaTempDir) })
+
+	fh1 := newTestFileHeader(t, "video.mp4", []byte("first upload"))
+	fh2 := newTestFileHeader(t, "video.mp4", []byte("second upload, different bytes"))
+
+	oid1, path1, err := saveWithOID(fh1)
+	if err != nil {
+		t.Fatalf("saveWithOID (first): %v", err)
+	}
+	oid2, path2, err := saveWithOID(fh2)
+	if err != nil {
+		t.Fatalf("saveWithOID (second): %v", err)
+	}
+
+	// Two concurrent uploads sharing a filename must not collide on the same
+	// staging path, or one would truncate and corrupt the other.
+	if path1 == path2 {
+		t.Fatalf("expected distinct staging paths for same-named uploads, both got %s", path1)
+	}
+	if oid1 == oid2 {
+		t.Fatalf("expected distinct OIDs for different content")
+	}
+
+	data1, err := os.ReadFile(path1)
+	if err != nil {
+		t.Fatalf("read staged file 1: %v", err)
+	}
+	if string(data1) != "first upload" {
+		t.Errorf("staged file 1 has wrong content: %q", data1)
+	}
+
+	data2, err := os.ReadFile(path2)
+	if err != nil {
+		t.Fatalf("read staged file 2: %v", err)
+	}
+	if string(data2) != "second upload, different bytes" {
+		t.Errorf("staged file 2 has wrong content: %q", data2)
+	}
+
+	os.Remove(path1)
+	os.Remove(path2)
+}
+
+func TestObjectPathSanitizesFilename(t *testing.T) {
+	cases := []struct {
+		name     string
+		filename string
+	}{
+		{"path traversal", "../../../../etc/cron.d/evil.mp4"},
+		{"absolute path", "/etc/passwd"},
+		{"empty", ""},
+		{"dot", "."},
+		{"dotdot", ".."},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := objectPath("deadbeef", tc.filename)
+			dir := filepath.Join(MediaTempDir, "deadbeef")
+			rel, err := filepath.Rel(dir, got)
+			if err != nil {
+				t.Fatalf("filepath.Rel: %v", err)
+			}
+			if rel == ".." || filepath.IsAbs(rel) || bytes.Contains([]byte(rel), []byte("..")) {
+				t.Errorf("objectPath(%q) = %q, escapes object dir %q", tc.filename, got, dir)
+			}
+		})
+	}
+}
+
+func TestCommitObjectDedupesOnExistingOID(t *testing.T) {
+	t.Cleanup(func() { os.RemoveAll(MediaTempDir) })
+
+	const oid = "cafebabe"
+
+	fh := newTestFileHeader(t, "clip.mp4", []byte("winning bytes"))
+	_, stagingPath, err := saveWithOID(fh)
+	if err != nil {
+		t.Fatalf("saveWithOID: %v", err)
+	}
+
+	dest, err := commitObject(stagingPath, oid, "clip.mp4")
+	if err != nil {
+		t.Fatalf("commitObject (first): %v", err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Fatalf("committed object missing: %v", err)
+	}
+
+	// A second upload racing to commit the same OID should discard its
+	// staging copy and keep the bytes that already won.
+	fh2 := newTestFileHeader(t, "clip.mp4", []byte("losing bytes"))
+	_, stagingPath2, err := saveWithOID(fh2)
+	if err != nil {
+		t.Fatalf("saveWithOID (second): %v", err)
+	}
+
+	dest2, err := commitObject(stagingPath2, oid, "clip.mp4")
+	if err != nil {
+		t.Fatalf("commitObject (second): %v", err)
+	}
+	if dest2 != dest {
+		t.Fatalf("expected second commit to resolve to the same path, got %s vs %s", dest2, dest)
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read committed object: %v", err)
+	}
+	if string(data) != "winning bytes" {
+		t.Errorf("expected the first committer's bytes to win, got %q", data)
+	}
+	if _, err := os.Stat(stagingPath2); !os.IsNotExist(err) {
+		t.Errorf("expected losing staging copy to be removed")
+	}
+}
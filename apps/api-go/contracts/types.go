@@ -95,6 +95,9 @@ type OrchestratorResponse struct {
 	AgentBreakdown   []AgentResponse `json:"agent_breakdown" binding:"required"`
 	ProcessingTimeMs int64           `json:"processing_time_ms"`
 	Timestamp        time.Time       `json:"timestamp"`
+	// ContentOID is the hex-encoded SHA-256 of the uploaded media, used to
+	// dedupe repeat uploads of the same file across request IDs.
+	ContentOID string `json:"content_oid,omitempty"`
 }
 
 // AgentWeights defines the weights for each agent in consensus
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"truthnet/api-go/contracts"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// JobWorkers controls how many analysis jobs can run concurrently.
+const JobWorkers = 4
+
+// analysisJob describes a unit of work submitted by assembleAndDispatch.
+type analysisJob struct {
+	requestID    string
+	oid          string
+	mediaPath    string
+	mediaType    contracts.MediaType
+	startTime    time.Time
+	callbackURL  string
+	callbackAuth string
+}
+
+// JobManager fans analysis jobs out to a worker pool and tracks which
+// request IDs are still in flight so getResultHandler can tell "pending"
+// apart from "unknown".
+type JobManager struct {
+	store   ResultStore
+	queue   chan analysisJob
+	mu      sync.Mutex
+	pending map[string]time.Time
+}
+
+// NewJobManager starts workerCount goroutines pulling from an internal queue.
+func NewJobManager(store ResultStore, workerCount int) *JobManager {
+	jm := &JobManager{
+		store:   store,
+		queue:   make(chan analysisJob, 64),
+		pending: make(map[string]time.Time),
+	}
+
+	for i := 0; i < workerCount; i++ {
+		go jm.worker()
+	}
+
+	return jm
+}
+
+// Submit enqueues a job for processing and immediately marks it pending.
+func (jm *JobManager) Submit(job analysisJob) {
+	jm.mu.Lock()
+	jm.pending[job.requestID] = job.startTime
+	jm.mu.Unlock()
+
+	jm.queue <- job
+}
+
+// IsPending reports whether requestID has been submitted but not yet stored.
+func (jm *JobManager) IsPending(requestID string) bool {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	_, ok := jm.pending[requestID]
+	return ok
+}
+
+func (jm *JobManager) worker() {
+	for job := range jm.queue {
+		jm.process(job)
+	}
+}
+
+// process runs the agent fan-out for a submitted job. It deliberately starts
+// from context.Background() rather than the submitting request's context:
+// the handler answers 202 Accepted right after Submit without waiting for
+// the job, so c.Request.Context() is canceled while the job is still queued
+// or running, and threading it through would only cut the agent calls
+// short. Per-agent and overall deadlines
+// still apply via analyzeDeadline/visualAgentTimeout/metadataAgentTimeout.
+func (jm *JobManager) process(job analysisJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), analyzeDeadline())
+	defer cancel()
+
+	var visualResponse, metadataResponse *contracts.AgentResponse
+
+	// Fan the two agent calls out concurrently so a slow agent no longer
+	// serializes the other; each still gets its own per-agent deadline.
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		visualCtx, cancel := context.WithTimeout(gctx, visualAgentTimeout())
+		defer cancel()
+		visualResponse = callVisualAgent(visualCtx, job.requestID, job.mediaPath, job.mediaType)
+		return nil
+	})
+
+	g.Go(func() error {
+		metadataCtx, cancel := context.WithTimeout(gctx, metadataAgentTimeout())
+		defer cancel()
+		metadataResponse = callMetadataAgent(metadataCtx, job.requestID, job.mediaPath, job.mediaType)
+		return nil
+	})
+
+	g.Wait()
+
+	orchestratorResp := calculateVerdict(job.requestID, visualResponse, metadataResponse, job.startTime)
+	orchestratorResp.ContentOID = job.oid
+
+	if err := jm.store.Put(job.requestID, job.oid, orchestratorResp); err != nil {
+		fmt.Printf("⚠️  Failed to persist result for %s: %v\n", job.requestID, err)
+	}
+
+	jm.mu.Lock()
+	delete(jm.pending, job.requestID)
+	jm.mu.Unlock()
+
+	if job.callbackURL != "" && callbackManager != nil {
+		callbackManager.Enqueue(job.requestID, job.callbackURL, job.callbackAuth, orchestratorResp)
+	}
+}
+
+// sweepExpired removes content-addressed media directories under
+// MediaTempDir that haven't been modified within ttl, freeing uploaded
+// bytes once results have had a chance to be collected.
+func sweepExpired(ttl time.Duration) {
+	entries, err := os.ReadDir(MediaTempDir)
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		// A chunked upload's directory (MediaTempDir/<requestID>) only grows
+		// via appends to its part file, which doesn't bump the directory's
+		// own mtime. Without this check a multi-day resumable upload -
+		// exactly the scenario chunk0-6 exists to support - would look
+		// "expired" to the mtime-based sweep below and get deleted mid-transfer.
+		if uploads != nil {
+			if _, inFlight := uploads.get(entry.Name()); inFlight {
+				continue
+			}
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(MediaTempDir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			fmt.Printf("⚠️  Failed to sweep %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("🧹 Swept expired media: %s\n", path)
+	}
+}
@@ -9,16 +9,29 @@ import (
 	"time"
 
 	"truthnet/api-go/contracts"
+	"truthnet/api-go/render"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 const (
-	MaxFileSize      = 100 * 1024 * 1024 // 100MB
-	MediaTempDir     = "./tmp/media"
-	VisualAgentURL   = "http://localhost:8001"
-	MetadataAgentURL = "http://localhost:8002"
+	MaxFileSize            = 100 * 1024 * 1024 // 100MB
+	MediaTempDir           = "./tmp/media"
+	ResultStorePath        = "./tmp/results.db"
+	VisualAgentURL         = "http://localhost:8001"
+	MetadataAgentURL       = "http://localhost:8002"
+	DefaultResultTTL       = 24 * time.Hour
+	DefaultAgentTimeout    = 20 * time.Second
+	DefaultAnalyzeDeadline = 45 * time.Second
+)
+
+// resultStore and jobManager are wired up once in main and shared by the
+// HTTP handlers below.
+var (
+	resultStore     ResultStore
+	jobManager      *JobManager
+	callbackManager *CallbackManager
+	uploads         *uploadManager
 )
 
 func main() {
@@ -27,6 +40,20 @@ func main() {
 		log.Fatalf("Failed to create temp directory: %v", err)
 	}
 
+	store, err := NewBoltResultStore(ResultStorePath)
+	if err != nil {
+		log.Fatalf("Failed to open result store: %v", err)
+	}
+	defer store.Close()
+	resultStore = store
+
+	jobManager = NewJobManager(resultStore, JobWorkers)
+	callbackManager = NewCallbackManager(resultStore)
+	callbackManager.ResumePending()
+	uploads = newUploadManager(resultStore)
+
+	go sweepLoop(resultTTL())
+
 	// Initialize Gin router
 	router := gin.Default()
 
@@ -42,6 +69,19 @@ func main() {
 	// Get result endpoint
 	router.GET("/result/:request_id", getResultHandler)
 
+	// Delete a stored result
+	router.DELETE("/result/:request_id", deleteResultHandler)
+
+	// Inspect webhook delivery attempts
+	router.GET("/callbacks/:request_id", getCallbackHandler)
+
+	// Two-phase chunked/resumable upload protocol, for files too large to
+	// push through a single multipart request.
+	router.POST("/uploads", createUploadHandler)
+	router.PATCH("/uploads/:id", patchUploadHandler)
+	router.HEAD("/uploads/:id", headUploadHandler)
+	router.POST("/uploads/:id/verify", verifyUploadHandler)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000"
@@ -57,8 +97,51 @@ func main() {
 	}
 }
 
+// durationFromEnv parses a Go duration string (e.g. "30s") from the named
+// environment variable, falling back to def if unset or invalid.
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// resultTTL reads RESULT_TTL (a Go duration string, e.g. "24h") or falls
+// back to DefaultResultTTL.
+func resultTTL() time.Duration {
+	return durationFromEnv("RESULT_TTL", DefaultResultTTL)
+}
+
+// analyzeDeadline reads ANALYZE_DEADLINE, the overall budget for a job's
+// agent fan-out, or falls back to DefaultAnalyzeDeadline.
+func analyzeDeadline() time.Duration {
+	return durationFromEnv("ANALYZE_DEADLINE", DefaultAnalyzeDeadline)
+}
+
+// visualAgentTimeout reads VISUAL_AGENT_TIMEOUT or falls back to DefaultAgentTimeout.
+func visualAgentTimeout() time.Duration {
+	return durationFromEnv("VISUAL_AGENT_TIMEOUT", DefaultAgentTimeout)
+}
+
+// metadataAgentTimeout reads METADATA_AGENT_TIMEOUT or falls back to DefaultAgentTimeout.
+func metadataAgentTimeout() time.Duration {
+	return durationFromEnv("METADATA_AGENT_TIMEOUT", DefaultAgentTimeout)
+}
+
+// sweepLoop periodically removes expired media directories until the
+// process exits.
+func sweepLoop(ttl time.Duration) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		sweepExpired(ttl)
+	}
+}
+
 func healthHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	render.Negotiate(c, http.StatusOK, gin.H{
 		"status":  "healthy",
 		"service": "TruthNet API Server",
 		"version": "1.0.0",
@@ -95,21 +178,18 @@ func analyzeHandler(c *gin.Context) {
 		return
 	}
 
-	// Generate unique request ID
-	requestID := uuid.New().String()
-
-	// Create request-specific directory
-	requestDir := filepath.Join(MediaTempDir, requestID)
-	if err := os.MkdirAll(requestDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create storage directory",
-		})
-		return
+	callbackURL := c.PostForm("callback_url")
+	if callbackURL != "" {
+		if err := validateCallbackURL(callbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback_url", "details": err.Error()})
+			return
+		}
 	}
 
-	// Save uploaded file
-	mediaPath := filepath.Join(requestDir, file.Filename)
-	if err := c.SaveUploadedFile(file, mediaPath); err != nil {
+	// Stream the upload to a staging file while computing its content OID,
+	// so identical uploads can be deduplicated before hitting the agents.
+	oid, stagingPath, err := saveWithOID(file)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to save file",
 			"details": err.Error(),
@@ -117,35 +197,73 @@ func analyzeHandler(c *gin.Context) {
 		return
 	}
 
-	// Get absolute path
-	absPath, err := filepath.Abs(mediaPath)
+	assembleAndDispatch(c, oid, stagingPath, file.Filename, mediaType, startTime, callbackURL, c.PostForm("callback_auth"))
+}
+
+func getResultHandler(c *gin.Context) {
+	requestID := c.Param("request_id")
+
+	resp, found, err := resultStore.Get(requestID)
 	if err != nil {
-		absPath = mediaPath
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read result store"})
+		return
+	}
+
+	if found {
+		render.Negotiate(c, http.StatusOK, resp)
+		return
 	}
 
-	fmt.Printf("📁 File saved: %s (%.2f MB)\n", file.Filename, float64(file.Size)/(1024*1024))
-	fmt.Printf("🔍 Request ID: %s\n", requestID)
-	fmt.Printf("📊 Media Type: %s\n", mediaType)
+	if jobManager.IsPending(requestID) {
+		c.Header("Retry-After", "2")
+		render.Negotiate(c, http.StatusOK, gin.H{
+			"request_id": requestID,
+			"status":     "pending",
+		})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Unknown request_id"})
+}
+
+func deleteResultHandler(c *gin.Context) {
+	requestID := c.Param("request_id")
 
-	// Call agents in parallel
-	visualResponse := callVisualAgent(requestID, absPath, mediaType)
-	metadataResponse := callMetadataAgent(requestID, absPath, mediaType)
+	_, found, err := resultStore.Get(requestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read result store"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown request_id"})
+		return
+	}
 
-	// Calculate orchestrator response
-	orchestratorResp := calculateVerdict(requestID, visualResponse, metadataResponse, startTime)
+	// This only drops requestID's alias; the underlying content-addressed
+	// object and result may still be referenced by other request IDs, so
+	// media cleanup is left to sweepExpired.
+	if err := resultStore.Delete(requestID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete result"})
+		return
+	}
 
-	c.JSON(http.StatusOK, orchestratorResp)
+	c.Status(http.StatusNoContent)
 }
 
-func getResultHandler(c *gin.Context) {
+func getCallbackHandler(c *gin.Context) {
 	requestID := c.Param("request_id")
 
-	// For now, this is a placeholder
-	// In production, you'd store results in a database
-	c.JSON(http.StatusOK, gin.H{
-		"request_id": requestID,
-		"message":    "Result retrieval not yet implemented",
-	})
+	cb, found, err := resultStore.GetCallback(requestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read callback state"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No callback registered for request_id"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cb)
 }
 
 func detectMediaType(filename string) contracts.MediaType {
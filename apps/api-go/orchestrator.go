@@ -13,7 +13,11 @@ const (
 	MetadataWeight = 0.55
 )
 
-// calculateVerdict combines agent responses into final verdict
+// calculateVerdict combines agent responses into final verdict. It takes no
+// context.Context: it does pure in-memory computation from already-returned
+// agent responses, and by the time a worker reaches it the submitting
+// request has long since gotten its 202 Accepted (chunk0-1), so there is no
+// live client connection left to cancel against.
 func calculateVerdict(
 	requestID string,
 	visualResp *contracts.AgentResponse,
@@ -99,27 +103,39 @@ func calculateConfidence(
 
 	// Increase confidence if both agents succeeded
 	successCount := 0
-	if visualResp != nil && visualResp.Status == contracts.StatusSuccess {
-		successCount++
+	partialCount := 0
+	if visualResp != nil {
+		if visualResp.Status == contracts.StatusSuccess {
+			successCount++
+		} else if visualResp.Status == contracts.StatusPartial {
+			partialCount++
+		}
 	}
-	if metadataResp != nil && metadataResp.Status == contracts.StatusSuccess {
-		successCount++
+	if metadataResp != nil {
+		if metadataResp.Status == contracts.StatusSuccess {
+			successCount++
+		} else if metadataResp.Status == contracts.StatusPartial {
+			partialCount++
+		}
 	}
 
-	if successCount == 2 {
+	switch {
+	case successCount == 2:
 		confidence = 0.8
 
 		// Check if agents agree (within 0.2 risk score)
-		if visualResp != nil && metadataResp != nil {
-			scoreDiff := abs(visualResp.RiskScore - metadataResp.RiskScore)
-			if scoreDiff < 0.2 {
-				confidence = 0.95 // High confidence when agents agree
-			} else if scoreDiff < 0.4 {
-				confidence = 0.85 // Medium confidence
-			}
+		scoreDiff := abs(visualResp.RiskScore - metadataResp.RiskScore)
+		if scoreDiff < 0.2 {
+			confidence = 0.95 // High confidence when agents agree
+		} else if scoreDiff < 0.4 {
+			confidence = 0.85 // Medium confidence
 		}
-	} else if successCount == 1 {
+	case successCount == 1 && partialCount == 1:
+		confidence = 0.5 // One agent timed out, can't cross-check the other
+	case successCount == 1:
 		confidence = 0.6 // Lower confidence with only one agent
+	case partialCount > 0:
+		confidence = 0.3 // Neither agent finished in time
 	}
 
 	return confidence
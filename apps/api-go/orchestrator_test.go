@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"truthnet/api-go/contracts"
+)
+
+func TestCalculateVerdictPartial(t *testing.T) {
+	t.Run("one agent deadline-exceeded yields best-effort verdict with lowered confidence", func(t *testing.T) {
+		visual := &contracts.AgentResponse{
+			RequestID: "req-1",
+			AgentType: contracts.AgentTypeVisual,
+			Status:    contracts.StatusSuccess,
+			RiskScore: 0.8,
+		}
+		metadata := &contracts.AgentResponse{
+			RequestID: "req-1",
+			AgentType: contracts.AgentTypeMetadata,
+			Status:    contracts.StatusPartial,
+			Error:     &contracts.AgentError{Code: "DEADLINE_EXCEEDED", Message: "metadata agent timed out"},
+		}
+
+		resp := calculateVerdict("req-1", visual, metadata, time.Now())
+
+		if resp.RiskScore != 0.8 {
+			t.Errorf("expected risk score to come solely from the visual agent, got %.2f", resp.RiskScore)
+		}
+		if resp.Confidence != 0.5 {
+			t.Errorf("expected confidence 0.5 when one agent succeeded and the other timed out, got %.2f", resp.Confidence)
+		}
+		if len(resp.AgentBreakdown) != 2 {
+			t.Errorf("expected both agent responses (including the partial one) in the breakdown, got %d", len(resp.AgentBreakdown))
+		}
+	})
+
+	t.Run("both agents deadline-exceeded yields lowest confidence and zero score", func(t *testing.T) {
+		visual := &contracts.AgentResponse{
+			RequestID: "req-2",
+			AgentType: contracts.AgentTypeVisual,
+			Status:    contracts.StatusPartial,
+			Error:     &contracts.AgentError{Code: "DEADLINE_EXCEEDED"},
+		}
+		metadata := &contracts.AgentResponse{
+			RequestID: "req-2",
+			AgentType: contracts.AgentTypeMetadata,
+			Status:    contracts.StatusPartial,
+			Error:     &contracts.AgentError{Code: "DEADLINE_EXCEEDED"},
+		}
+
+		resp := calculateVerdict("req-2", visual, metadata, time.Now())
+
+		if resp.RiskScore != 0.0 {
+			t.Errorf("expected risk score 0 when neither agent contributed a score, got %.2f", resp.RiskScore)
+		}
+		if resp.Confidence != 0.3 {
+			t.Errorf("expected confidence 0.3 when neither agent finished in time, got %.2f", resp.Confidence)
+		}
+		if resp.Verdict != contracts.VerdictAuthentic {
+			t.Errorf("expected a low-score default verdict, got %s", resp.Verdict)
+		}
+	})
+
+	t.Run("both agents succeed and agree yields high confidence", func(t *testing.T) {
+		visual := &contracts.AgentResponse{
+			RequestID: "req-3",
+			AgentType: contracts.AgentTypeVisual,
+			Status:    contracts.StatusSuccess,
+			RiskScore: 0.7,
+		}
+		metadata := &contracts.AgentResponse{
+			RequestID: "req-3",
+			AgentType: contracts.AgentTypeMetadata,
+			Status:    contracts.StatusSuccess,
+			RiskScore: 0.65,
+		}
+
+		resp := calculateVerdict("req-3", visual, metadata, time.Now())
+
+		if resp.Confidence != 0.95 {
+			t.Errorf("expected confidence 0.95 when both agents agree, got %.2f", resp.Confidence)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+// Package render provides content negotiation shared by handlers that
+// return potentially large verdict payloads.
+package render
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// ContentTypeJSON is the default, unversioned response representation.
+	ContentTypeJSON = "application/json"
+	// ContentTypeVerdict is the versioned JSON representation.
+	ContentTypeVerdict = "application/vnd.truthnet.verdict+json"
+	// ContentTypeMsgpack is a compact binary representation for bandwidth-
+	// sensitive clients.
+	ContentTypeMsgpack = "application/msgpack"
+
+	// DefaultGzipThresholdBytes mirrors Kubernetes' defaultGzipThresholdBytes:
+	// bodies smaller than this aren't worth the CPU cost of compressing.
+	DefaultGzipThresholdBytes = 128 * 1024
+)
+
+// Negotiate marshals obj according to the request's Accept header (plain
+// JSON, the versioned verdict type, or msgpack) and gzips the body when the
+// client advertises Accept-Encoding: gzip and the body is large enough to
+// be worth compressing. Every negotiated response carries a Vary header so
+// caches key on both Accept and Accept-Encoding.
+func Negotiate(c *gin.Context, status int, obj interface{}) {
+	c.Header("Vary", "Accept, Accept-Encoding")
+
+	contentType, body, err := marshal(c.GetHeader("Accept"), obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) && len(body) > gzipThreshold() {
+		if compressed, err := gzipBytes(body); err == nil {
+			c.Header("Content-Encoding", "gzip")
+			c.Data(status, contentType, compressed)
+			return
+		}
+	}
+
+	c.Data(status, contentType, body)
+}
+
+func marshal(accept string, obj interface{}) (string, []byte, error) {
+	switch {
+	case strings.Contains(accept, ContentTypeMsgpack):
+		body, err := msgpack.Marshal(obj)
+		return ContentTypeMsgpack, body, err
+	case strings.Contains(accept, ContentTypeVerdict):
+		body, err := json.Marshal(obj)
+		return ContentTypeVerdict, body, err
+	default:
+		body, err := json.Marshal(obj)
+		return ContentTypeJSON, body, err
+	}
+}
+
+func acceptsGzip(header string) bool {
+	for _, enc := range strings.Split(header, ",") {
+		if strings.TrimSpace(strings.SplitN(enc, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func gzipThreshold() int {
+	if v := os.Getenv("GZIP_THRESHOLD_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return DefaultGzipThresholdBytes
+}
+
+func gzipBytes(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"truthnet/api-go/contracts"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// resultsBucket holds OrchestratorResponses keyed by content OID, so
+	// repeat uploads of the same file share a single stored verdict.
+	resultsBucket = []byte("results")
+	// requestIndexBucket maps a request_id to the OID that holds its result,
+	// letting several request IDs alias the same analysis.
+	requestIndexBucket = []byte("request_index")
+	// callbacksBucket holds PersistedCallback records keyed by request_id, so
+	// retry state survives a server restart.
+	callbacksBucket = []byte("callbacks")
+	// uploadsBucket holds PersistedUpload records keyed by request_id, so
+	// in-flight chunked uploads can be resumed after a restart.
+	uploadsBucket = []byte("uploads")
+)
+
+// ResultStore persists OrchestratorResponses so results can be retrieved
+// asynchronously after the agent fan-out completes. Results are addressed
+// by content OID; request IDs are indirections onto that content so that
+// deduplicated uploads can still be polled by their own request_id.
+// Implementations must be safe for concurrent use by the job workers and
+// the HTTP handlers.
+type ResultStore interface {
+	// Put stores resp under oid and indexes requestID to it.
+	Put(requestID, oid string, resp contracts.OrchestratorResponse) error
+	// Alias points requestID at an oid that already has a stored result.
+	Alias(requestID, oid string) error
+	// Get resolves requestID (via its OID index) to a stored result.
+	Get(requestID string) (contracts.OrchestratorResponse, bool, error)
+	// GetByOID looks up a result directly by content OID.
+	GetByOID(oid string) (contracts.OrchestratorResponse, bool, error)
+	List() ([]contracts.OrchestratorResponse, error)
+	// Delete removes requestID's index entry. The underlying OID result is
+	// left intact in case other request IDs still alias it.
+	Delete(requestID string) error
+
+	// PutCallback persists the delivery state of a webhook callback.
+	PutCallback(requestID string, cb CallbackDelivery) error
+	// GetCallback returns the delivery state for requestID, if any.
+	GetCallback(requestID string) (CallbackDelivery, bool, error)
+	// PendingCallbacks returns every persisted callback still in the
+	// "pending" state, so a restart can resume their delivery.
+	PendingCallbacks() ([]CallbackDelivery, error)
+
+	// PutUpload persists the progress of an in-flight chunked upload.
+	PutUpload(requestID string, p PersistedUpload) error
+	// ListUploads returns every persisted upload, so uploadManager can
+	// restore its in-memory state after a restart.
+	ListUploads() ([]PersistedUpload, error)
+	// DeleteUpload removes a completed or abandoned upload's persisted state.
+	DeleteUpload(requestID string) error
+}
+
+// BoltResultStore is a ResultStore backed by a local BoltDB file.
+type BoltResultStore struct {
+	db *bolt.DB
+}
+
+// NewBoltResultStore opens (creating if necessary) a BoltDB file at path.
+func NewBoltResultStore(path string) (*BoltResultStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open result store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(resultsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(requestIndexBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(callbacksBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(uploadsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init result store: %w", err)
+	}
+
+	return &BoltResultStore{db: db}, nil
+}
+
+// Put stores resp under oid and indexes requestID to it, overwriting any
+// existing entries.
+func (s *BoltResultStore) Put(requestID, oid string, resp contracts.OrchestratorResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("marshal result: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(resultsBucket).Put([]byte(oid), data); err != nil {
+			return err
+		}
+		return tx.Bucket(requestIndexBucket).Put([]byte(requestID), []byte(oid))
+	})
+}
+
+// Alias points requestID at an oid that already has a stored result.
+func (s *BoltResultStore) Alias(requestID, oid string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(requestIndexBucket).Put([]byte(requestID), []byte(oid))
+	})
+}
+
+// Get returns the stored response for requestID, if any.
+func (s *BoltResultStore) Get(requestID string) (contracts.OrchestratorResponse, bool, error) {
+	var resp contracts.OrchestratorResponse
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		oid := tx.Bucket(requestIndexBucket).Get([]byte(requestID))
+		if oid == nil {
+			return nil
+		}
+
+		data := tx.Bucket(resultsBucket).Get(oid)
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &resp)
+	})
+	if err != nil {
+		return resp, false, fmt.Errorf("get result: %w", err)
+	}
+
+	return resp, found, nil
+}
+
+// GetByOID looks up a result directly by content OID, bypassing the
+// request_id index. Used to detect duplicate uploads before dispatching
+// to the agents.
+func (s *BoltResultStore) GetByOID(oid string) (contracts.OrchestratorResponse, bool, error) {
+	var resp contracts.OrchestratorResponse
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(resultsBucket).Get([]byte(oid))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &resp)
+	})
+	if err != nil {
+		return resp, false, fmt.Errorf("get result by oid: %w", err)
+	}
+
+	return resp, found, nil
+}
+
+// List returns every distinct stored result. Intended for debugging/admin
+// use; callers processing large result sets should add pagination if needed.
+func (s *BoltResultStore) List() ([]contracts.OrchestratorResponse, error) {
+	var all []contracts.OrchestratorResponse
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultsBucket).ForEach(func(_, data []byte) error {
+			var resp contracts.OrchestratorResponse
+			if err := json.Unmarshal(data, &resp); err != nil {
+				return err
+			}
+			all = append(all, resp)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list results: %w", err)
+	}
+
+	return all, nil
+}
+
+// Delete removes requestID's index entry. The underlying OID result is
+// left intact in case other request IDs still alias it.
+func (s *BoltResultStore) Delete(requestID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(requestIndexBucket).Delete([]byte(requestID))
+	})
+}
+
+// PutCallback persists the delivery state of a webhook callback, including
+// its bearer auth token (CallbackDelivery.toPersisted) since a resumed
+// delivery after a restart needs it to reconstruct the Authorization header.
+func (s *BoltResultStore) PutCallback(requestID string, cb CallbackDelivery) error {
+	data, err := json.Marshal(cb.toPersisted())
+	if err != nil {
+		return fmt.Errorf("marshal callback: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(callbacksBucket).Put([]byte(requestID), data)
+	})
+}
+
+// GetCallback returns the delivery state for requestID, if any. The
+// returned CallbackDelivery withholds Auth (json:"-") so callers that hand
+// it straight back to an API response - namely getCallbackHandler - never
+// leak the token.
+func (s *BoltResultStore) GetCallback(requestID string) (CallbackDelivery, bool, error) {
+	var p PersistedCallback
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(callbacksBucket).Get([]byte(requestID))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &p)
+	})
+	if err != nil {
+		return CallbackDelivery{}, false, fmt.Errorf("get callback: %w", err)
+	}
+
+	return p.toDelivery(), found, nil
+}
+
+// PendingCallbacks returns every persisted callback still in the "pending"
+// state, so CallbackManager.ResumePending can resume their delivery after a
+// restart.
+func (s *BoltResultStore) PendingCallbacks() ([]CallbackDelivery, error) {
+	var pending []CallbackDelivery
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(callbacksBucket).ForEach(func(_, data []byte) error {
+			var p PersistedCallback
+			if err := json.Unmarshal(data, &p); err != nil {
+				return err
+			}
+			if p.Status == "pending" {
+				pending = append(pending, p.toDelivery())
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list pending callbacks: %w", err)
+	}
+
+	return pending, nil
+}
+
+// PutUpload persists the progress of an in-flight chunked upload, keyed by
+// its request ID, overwriting any previously stored progress.
+func (s *BoltResultStore) PutUpload(requestID string, p PersistedUpload) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal upload: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadsBucket).Put([]byte(requestID), data)
+	})
+}
+
+// ListUploads returns every persisted upload, so uploadManager can restore
+// its in-memory state on startup.
+func (s *BoltResultStore) ListUploads() ([]PersistedUpload, error) {
+	var all []PersistedUpload
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadsBucket).ForEach(func(_, data []byte) error {
+			var p PersistedUpload
+			if err := json.Unmarshal(data, &p); err != nil {
+				return err
+			}
+			all = append(all, p)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list uploads: %w", err)
+	}
+
+	return all, nil
+}
+
+// DeleteUpload removes a completed or abandoned upload's persisted state.
+func (s *BoltResultStore) DeleteUpload(requestID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadsBucket).Delete([]byte(requestID))
+	})
+}
+
+// Close releases the underlying BoltDB file handle.
+func (s *BoltResultStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"truthnet/api-go/contracts"
+	"truthnet/api-go/render"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DefaultChunkSize is the chunk size advertised to clients of the
+// two-phase upload protocol; it's advisory, PATCH accepts any chunk that
+// starts at the next expected offset.
+const DefaultChunkSize = 8 * 1024 * 1024 // 8MB
+
+var contentRangeRe = regexp.MustCompile(`^bytes (\d+)-(\d+)/(\d+)$`)
+
+// uploadState tracks a single resumable upload's progress. The received
+// bytes live on disk at partPath so HEAD can report the next offset after
+// a restart. Its static fields are mirrored into PersistedUpload after each
+// chunk; received and the hash-so-far are not stored there but recomputed
+// from partPath on restore, so a restart can resume mid-transfer.
+type uploadState struct {
+	mu           sync.Mutex
+	requestID    string
+	filename     string
+	expectedSize int64
+	expectedSHA  string
+	mediaType    contracts.MediaType
+	received     int64
+	hasher       hash.Hash
+	partPath     string
+}
+
+// PersistedUpload is the on-disk record of an uploadState's progress,
+// stored in the result store's uploads bucket so uploadManager can restore
+// in-flight uploads after a restart.
+type PersistedUpload struct {
+	RequestID    string `json:"request_id"`
+	Filename     string `json:"filename"`
+	ExpectedSize int64  `json:"expected_size"`
+	ExpectedSHA  string `json:"expected_sha"`
+	MediaType    string `json:"media_type"`
+	PartPath     string `json:"part_path"`
+}
+
+// uploadManager tracks in-flight chunked uploads by request ID.
+type uploadManager struct {
+	mu      sync.Mutex
+	uploads map[string]*uploadState
+	store   ResultStore
+}
+
+// newUploadManager restores any uploads left in-flight by a previous process
+// before returning, recomputing the hash-so-far from each part file's bytes
+// since the hasher itself can't be serialized.
+func newUploadManager(store ResultStore) *uploadManager {
+	um := &uploadManager{uploads: make(map[string]*uploadState), store: store}
+
+	persisted, err := store.ListUploads()
+	if err != nil {
+		fmt.Printf("⚠️  Failed to list persisted uploads: %v\n", err)
+		return um
+	}
+
+	for _, p := range persisted {
+		state := &uploadState{
+			requestID:    p.RequestID,
+			filename:     p.Filename,
+			expectedSize: p.ExpectedSize,
+			expectedSHA:  p.ExpectedSHA,
+			mediaType:    contracts.MediaType(p.MediaType),
+			hasher:       sha256.New(),
+			partPath:     p.PartPath,
+		}
+
+		if f, err := os.Open(p.PartPath); err == nil {
+			n, err := io.Copy(state.hasher, f)
+			f.Close()
+			if err != nil {
+				fmt.Printf("⚠️  Failed to rehash part file for upload %s: %v\n", p.RequestID, err)
+				continue
+			}
+			state.received = n
+		} else {
+			fmt.Printf("⚠️  Failed to open part file for upload %s: %v\n", p.RequestID, err)
+			continue
+		}
+
+		um.uploads[p.RequestID] = state
+		fmt.Printf("♻️  Resumed upload %s at offset %d\n", p.RequestID, state.received)
+	}
+
+	return um
+}
+
+func (um *uploadManager) create(filename string, size int64, sha, mediaType string) (*uploadState, error) {
+	requestID := uuid.New().String()
+
+	dir := filepath.Join(MediaTempDir, requestID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+
+	partPath := filepath.Join(dir, "part")
+	f, err := os.Create(partPath)
+	if err != nil {
+		return nil, fmt.Errorf("create part file: %w", err)
+	}
+	f.Close()
+
+	state := &uploadState{
+		requestID:    requestID,
+		filename:     filename,
+		expectedSize: size,
+		expectedSHA:  strings.ToLower(sha),
+		mediaType:    contracts.MediaType(mediaType),
+		hasher:       sha256.New(),
+		partPath:     partPath,
+	}
+
+	if err := um.persist(state); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("persist upload: %w", err)
+	}
+
+	um.mu.Lock()
+	um.uploads[requestID] = state
+	um.mu.Unlock()
+
+	return state, nil
+}
+
+func (um *uploadManager) get(requestID string) (*uploadState, bool) {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+	state, ok := um.uploads[requestID]
+	return state, ok
+}
+
+func (um *uploadManager) delete(requestID string) {
+	um.mu.Lock()
+	delete(um.uploads, requestID)
+	um.mu.Unlock()
+
+	if err := um.store.DeleteUpload(requestID); err != nil {
+		fmt.Printf("⚠️  Failed to delete persisted upload %s: %v\n", requestID, err)
+	}
+}
+
+// persist mirrors state's static fields into the result store. It does not
+// capture received/hash-so-far directly; those are recovered from partPath
+// on restore, since only the bytes on disk are guaranteed consistent with
+// whatever offset a restart catches the upload at.
+func (um *uploadManager) persist(state *uploadState) error {
+	return um.store.PutUpload(state.requestID, PersistedUpload{
+		RequestID:    state.requestID,
+		Filename:     state.filename,
+		ExpectedSize: state.expectedSize,
+		ExpectedSHA:  state.expectedSHA,
+		MediaType:    string(state.mediaType),
+		PartPath:     state.partPath,
+	})
+}
+
+// rehashPart rebuilds state.hasher from the bytes currently on disk at
+// partPath. Callers must hold state.mu. It's used to recover from a
+// partial/short chunk write, where the part file has just been truncated
+// back to state.received and the in-memory hasher (which already consumed
+// the partial bytes) needs to be rewound to match.
+func rehashPart(state *uploadState) error {
+	f, err := os.Open(state.partPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	state.hasher = h
+	return nil
+}
+
+type createUploadRequest struct {
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required"`
+	SHA256   string `json:"sha256" binding:"required"`
+}
+
+func createUploadHandler(c *gin.Context) {
+	var req createUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	if req.Size > MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("File too large. Max size: %d MB", MaxFileSize/(1024*1024)),
+		})
+		return
+	}
+
+	mediaType := detectMediaType(req.Filename)
+	if mediaType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported file type. Please upload an image or video.",
+		})
+		return
+	}
+
+	state, err := uploads.create(req.Filename, req.Size, req.SHA256, string(mediaType))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start upload", "details": err.Error()})
+		return
+	}
+
+	render.Negotiate(c, http.StatusCreated, gin.H{
+		"request_id": state.requestID,
+		"upload_url": "/uploads/" + state.requestID,
+		"chunk_size": DefaultChunkSize,
+	})
+}
+
+// receivedRangeHeader formats the "Range" value HEAD /uploads/:id and the
+// 409 offset-mismatch response use to tell a client where to resume. For a
+// freshly created upload (received == 0) there are zero bytes on disk, so
+// "bytes=0-<received-1>" would read "bytes=0--1" - not a valid byte-range -
+// and a client parsing it to compute the next offset gets garbage. RFC 7233
+// represents "no bytes yet" as "bytes */<size>".
+func receivedRangeHeader(received, expectedSize int64) string {
+	if received == 0 {
+		return fmt.Sprintf("bytes */%d", expectedSize)
+	}
+	return fmt.Sprintf("bytes=0-%d", received-1)
+}
+
+func patchUploadHandler(c *gin.Context) {
+	state, ok := uploads.get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown upload"})
+		return
+	}
+
+	m := contentRangeRe.FindStringSubmatch(c.GetHeader("Content-Range"))
+	if m == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or malformed Content-Range header"})
+		return
+	}
+	start, _ := strconv.ParseInt(m[1], 10, 64)
+	end, _ := strconv.ParseInt(m[2], 10, 64)
+	total, _ := strconv.ParseInt(m[3], 10, 64)
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if total != state.expectedSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-Range total does not match the size given to POST /uploads"})
+		return
+	}
+
+	if start != state.received {
+		c.Header("Range", receivedRangeHeader(state.received, state.expectedSize))
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "Chunk does not start at the expected offset",
+			"next_offset": state.received,
+		})
+		return
+	}
+
+	chunkLen := end - start + 1
+
+	if end >= state.expectedSize || start+chunkLen > state.expectedSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-Range exceeds the size given to POST /uploads"})
+		return
+	}
+
+	f, err := os.OpenFile(state.partPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open upload for writing"})
+		return
+	}
+	defer f.Close()
+
+	// Stream the chunk straight to the part file instead of buffering it in
+	// memory first; a client is free to PATCH an entire file in one request,
+	// and doing otherwise would reintroduce the whole-file-in-memory problem
+	// this two-phase protocol exists to avoid.
+	written, err := io.CopyN(io.MultiWriter(f, state.hasher), c.Request.Body, chunkLen)
+	if written != chunkLen {
+		// A short/interrupted body already appended partial bytes to the part
+		// file and fed them to the hasher; both must be rolled back to
+		// state.received or the next resumed PATCH appends at the wrong
+		// offset and verify's SHA-256 can never match.
+		if truncErr := f.Truncate(state.received); truncErr != nil {
+			fmt.Printf("⚠️  Failed to truncate part file for upload %s: %v\n", state.requestID, truncErr)
+		}
+		if rehashErr := rehashPart(state); rehashErr != nil {
+			fmt.Printf("⚠️  Failed to rebuild hasher for upload %s: %v\n", state.requestID, rehashErr)
+		}
+		if err != nil && err != io.EOF {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write chunk"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chunk length does not match Content-Range"})
+		return
+	}
+	state.received += written
+
+	if err := uploads.persist(state); err != nil {
+		fmt.Printf("⚠️  Failed to persist upload progress for %s: %v\n", state.requestID, err)
+	}
+
+	render.Negotiate(c, http.StatusOK, gin.H{
+		"request_id": state.requestID,
+		"received":   state.received,
+		"expected":   state.expectedSize,
+	})
+}
+
+func headUploadHandler(c *gin.Context) {
+	state, ok := uploads.get(c.Param("id"))
+	if !ok {
+		c.Header("Content-Length", "0")
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	c.Header("Range", receivedRangeHeader(state.received, state.expectedSize))
+	c.Header("X-TruthNet-Upload-Received", strconv.FormatInt(state.received, 10))
+	c.Header("X-TruthNet-Upload-Expected", strconv.FormatInt(state.expectedSize, 10))
+	// A HEAD response has no body, but without an explicit Content-Length
+	// gin commits to chunked Transfer-Encoding on WriteHeader and then never
+	// frames a body for the client to read, leaving it waiting on a chunk
+	// terminator that never arrives.
+	c.Header("Content-Length", "0")
+	c.Status(http.StatusOK)
+}
+
+type verifyUploadRequest struct {
+	CallbackURL  string `json:"callback_url"`
+	CallbackAuth string `json:"callback_auth"`
+}
+
+func verifyUploadHandler(c *gin.Context) {
+	requestID := c.Param("id")
+
+	state, ok := uploads.get(requestID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown upload"})
+		return
+	}
+
+	var req verifyUploadRequest
+	_ = c.ShouldBindJSON(&req) // callback fields are optional
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback_url", "details": err.Error()})
+			return
+		}
+	}
+
+	state.mu.Lock()
+	if state.received != state.expectedSize {
+		received, expected := state.received, state.expectedSize
+		state.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Upload incomplete",
+			"received": received,
+			"expected": expected,
+		})
+		return
+	}
+
+	oid := hex.EncodeToString(state.hasher.Sum(nil))
+	if oid != state.expectedSHA {
+		computed := oid
+		state.mu.Unlock()
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "SHA-256 mismatch",
+			"expected": state.expectedSHA,
+			"computed": computed,
+		})
+		return
+	}
+	filename, mediaType, partPath := state.filename, state.mediaType, state.partPath
+	state.mu.Unlock()
+
+	uploads.delete(requestID)
+
+	// Feed the assembled, verified file through the same assembler the
+	// single-shot POST /analyze uses, so there is one code path into the
+	// agent pipeline regardless of how the bytes arrived.
+	assembleAndDispatch(c, oid, partPath, filename, mediaType, time.Now(), req.CallbackURL, req.CallbackAuth)
+}